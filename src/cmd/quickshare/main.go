@@ -0,0 +1,65 @@
+// Command quickshare is the quickshare server entrypoint.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ihexxa/quickshare/src/db/rdb/rdbsql"
+)
+
+func main() {
+	var (
+		dbFlavor    = flag.String("db-flavor", "sqlite", `database flavor: "sqlite" or "postgres"`)
+		sqliteFile  = flag.String("sqlite-file", "quickshare.db", "path to the SQLite database file")
+		pgHost      = flag.String("pg-host", "localhost", "Postgres host")
+		pgPort      = flag.Int("pg-port", 5432, "Postgres port")
+		pgUser      = flag.String("pg-user", "", "Postgres user")
+		pgPassword  = flag.String("pg-password", "", "Postgres password")
+		pgDatabase  = flag.String("pg-database", "", "Postgres database name")
+		pgSSLMode   = flag.String("pg-sslmode", "disable", "Postgres sslmode")
+		migrateOnly = flag.Bool("migrate-only", false, "run pending schema migrations and exit, without starting the server")
+	)
+	flag.Parse()
+
+	cfg, err := storeConfig(*dbFlavor, *sqliteFile, *pgHost, *pgPort, *pgUser, *pgPassword, *pgDatabase, *pgSSLMode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Open runs every pending migration before returning, so opening the
+	// store is itself the migration step; --migrate-only just stops short of
+	// starting the server afterward.
+	st, err := cfg.Open()
+	if err != nil {
+		log.Fatalf("open store: %v", err)
+	}
+	if *migrateOnly {
+		fmt.Println("migrations applied, exiting")
+		os.Exit(0)
+	}
+
+	_ = st // the server would take over from here; not part of this tree
+}
+
+func storeConfig(flavor, sqliteFile, pgHost string, pgPort int, pgUser, pgPassword, pgDatabase, pgSSLMode string) (rdbsql.Config, error) {
+	switch flavor {
+	case "sqlite":
+		return &rdbsql.SQLite{File: sqliteFile}, nil
+	case "postgres":
+		return &rdbsql.Postgres{
+			Host:              pgHost,
+			Port:              pgPort,
+			User:              pgUser,
+			Password:          pgPassword,
+			Database:          pgDatabase,
+			SSLMode:           pgSSLMode,
+			ConnectionTimeout: 10 * time.Second,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown -db-flavor %q, want %q or %q", flavor, "sqlite", "postgres")
+	}
+}
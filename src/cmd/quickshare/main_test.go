@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestStoreConfigRejectsUnknownFlavor(t *testing.T) {
+	if _, err := storeConfig("mysql", "", "", 0, "", "", "", ""); err == nil {
+		t.Fatal("expected an error for an unknown -db-flavor")
+	}
+}
+
+func TestStoreConfigBuildsSQLite(t *testing.T) {
+	cfg, err := storeConfig("sqlite", "quickshare.db", "", 0, "", "", "", "")
+	if err != nil {
+		t.Fatalf("storeConfig: %v", err)
+	}
+	if cfg == nil {
+		t.Fatal("expected a non-nil Config")
+	}
+}
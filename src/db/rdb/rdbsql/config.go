@@ -0,0 +1,118 @@
+package rdbsql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/ihexxa/quickshare/src/db"
+	"github.com/ihexxa/quickshare/src/db/rdb/sqlutil"
+)
+
+// Flavor identifies the SQL dialect a Store talks to.
+type Flavor int
+
+const (
+	// FlavorSQLite is the embedded, single-file engine quickshare ships with by default.
+	FlavorSQLite Flavor = iota
+	// FlavorPostgres is for multi-node deployments where a shared server is required.
+	FlavorPostgres
+)
+
+// Config opens a flavor-specific connection and returns a ready-to-use user store.
+type Config interface {
+	Open() (db.IUserStore, error)
+}
+
+// SQLite opens a single-file SQLite database.
+type SQLite struct {
+	File string
+}
+
+// Open implements Config.
+func (c *SQLite) Open() (db.IUserStore, error) {
+	rawDB, err := sql.Open("sqlite3", c.File)
+	if err != nil {
+		return nil, err
+	}
+
+	// A second connection in the pool means a second, independent database
+	// for ":memory:" (and "file::memory:" without "cache=shared"), so a
+	// GetUser racing a migration on a different connection sees no tables at
+	// all. Cap the pool at one connection so every query goes through the
+	// same database regardless of file mode.
+	rawDB.SetMaxOpenConns(1)
+
+	// SQLite only allows one writer at a time at the file level, so all
+	// writes are serialized through a single Writer goroutine instead of a
+	// Go-level mutex.
+	st := &Store{
+		db:     rawDB,
+		writer: sqlutil.NewWriter(rawDB),
+		flavor: FlavorSQLite,
+	}
+	if err := st.init(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Postgres opens a connection to a PostgreSQL server.
+type Postgres struct {
+	Host              string
+	Port              int
+	User              string
+	Password          string
+	Database          string
+	SSLMode           string
+	ConnectionTimeout time.Duration
+	MaxOpenConns      int
+}
+
+// Open implements Config.
+func (c *Postgres) Open() (db.IUserStore, error) {
+	if c.SSLMode == "" {
+		c.SSLMode = "disable"
+	}
+
+	connStr := pqKV("host", c.Host) +
+		pqKV("port", strconv.Itoa(c.Port)) +
+		pqKV("user", c.User) +
+		pqKV("password", c.Password) +
+		pqKV("dbname", c.Database) +
+		pqKV("sslmode", c.SSLMode) +
+		pqKV("connect_timeout", strconv.Itoa(int(c.ConnectionTimeout.Seconds())))
+	rawDB, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxOpenConns > 0 {
+		rawDB.SetMaxOpenConns(c.MaxOpenConns)
+	}
+
+	// Postgres handles concurrent writers itself, so writes run as plain
+	// transactions rather than through a serializing Writer.
+	st := &Store{
+		db:     rawDB,
+		flavor: FlavorPostgres,
+	}
+	if err := st.init(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// pqKV formats a single libpq "keyword=value" pair, single-quoting the value
+// and escaping embedded backslashes and quotes per the keyword/value
+// connection string rules so a password or user containing a space, quote,
+// or backslash can't break the string or inject an extra key.
+func pqKV(key, val string) string {
+	val = strings.ReplaceAll(val, `\`, `\\`)
+	val = strings.ReplaceAll(val, `'`, `\'`)
+	return fmt.Sprintf("%s='%s' ", key, val)
+}
@@ -0,0 +1,11 @@
+package rdbsql
+
+import "testing"
+
+func TestPqKVEscapesSpecialChars(t *testing.T) {
+	got := pqKV("password", `p'a\ss word`)
+	want := `password='p\'a\\ss word' `
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
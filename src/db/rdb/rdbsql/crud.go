@@ -0,0 +1,361 @@
+package rdbsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ihexxa/quickshare/src/db"
+	"github.com/ihexxa/quickshare/src/db/rdb/sqlutil"
+)
+
+// Store is a flavor-agnostic implementation of db.IUserStore: the same CRUD
+// logic runs against SQLite or Postgres, with only placeholders and DDL
+// differing per flavor. Construct one through SQLite.Open() or
+// Postgres.Open() rather than directly.
+type Store struct {
+	db     db.IDB
+	writer *sqlutil.Writer // serializes writes; nil for flavors with a real concurrent writer (Postgres)
+	flavor Flavor
+}
+
+// RootUID is the fixed id of the root/admin account Init creates.
+const RootUID = uint64(1)
+
+// Init creates the root user with rootName/rootPwd the first time the store
+// is opened. It is a no-op if the store is already initialized, so restarting
+// against an existing database doesn't reset the admin password.
+func (st *Store) Init(ctx context.Context, rootName, rootPwd string) error {
+	if st.IsInited() {
+		return nil
+	}
+
+	return st.AddUser(ctx, &db.User{
+		ID:   RootUID,
+		Name: rootName,
+		Pwd:  rootPwd,
+		Role: db.AdminRole,
+	})
+}
+
+// IsInited reports whether the root user has already been created.
+func (st *Store) IsInited() bool {
+	_, err := st.GetUser(context.Background(), RootUID)
+	return err == nil
+}
+
+// write runs fn inside a transaction. For SQLite it is submitted to the
+// store's Writer so it is serialized with every other write against the same
+// file; for flavors that handle concurrent writers natively it just runs as
+// a plain transaction.
+func (st *Store) write(ctx context.Context, fn func(*sql.Tx) error) error {
+	if st.writer != nil {
+		return st.writer.Submit(ctx, fn)
+	}
+
+	txDB, ok := st.db.(interface {
+		BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return fmt.Errorf("db does not support transactions")
+	}
+
+	tx, err := txDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (st *Store) getUserTx(ctx context.Context, tx *sql.Tx, id uint64) (*db.User, error) {
+	user := &db.User{}
+	var quotaStr, preferenceStr string
+	query := `select id, name, pwd, role, used_space, quota, preference
+		from t_user
+		where id=?`
+	if st.flavor == FlavorPostgres {
+		// Postgres runs writes as concurrent transactions, so lock the row
+		// until this transaction ends.
+		query += ` for update`
+	}
+	err := tx.QueryRowContext(
+		ctx,
+		st.rebind(query),
+		id,
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Pwd,
+		&user.Role,
+		&user.UsedSpace,
+		&quotaStr,
+		&preferenceStr,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, db.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(quotaStr), &user.Quota); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(preferenceStr), &user.Preferences); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (st *Store) AddUser(ctx context.Context, user *db.User) error {
+	quotaStr, err := json.Marshal(user.Quota)
+	if err != nil {
+		return err
+	}
+	preferenceStr, err := json.Marshal(user.Preferences)
+	if err != nil {
+		return err
+	}
+
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`insert into t_user (id, name, pwd, role, used_space, quota, preference) values (?, ?, ?, ?, ?, ?, ?)`),
+			user.ID,
+			user.Name,
+			user.Pwd,
+			user.Role,
+			user.UsedSpace,
+			quotaStr,
+			preferenceStr,
+		)
+		return err
+	})
+}
+
+func (st *Store) DelUser(ctx context.Context, id uint64) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, st.rebind(`delete from t_user where id=?`), id)
+		return err
+	})
+}
+
+func (st *Store) GetUser(ctx context.Context, id uint64) (*db.User, error) {
+	user := &db.User{}
+	var quotaStr, preferenceStr string
+	err := st.db.QueryRowContext(
+		ctx,
+		st.rebind(`select id, name, pwd, role, used_space, quota, preference
+		from t_user
+		where id=?`),
+		id,
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Pwd,
+		&user.Role,
+		&user.UsedSpace,
+		&quotaStr,
+		&preferenceStr,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, db.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(quotaStr), &user.Quota); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(preferenceStr), &user.Preferences); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (st *Store) GetUserByName(ctx context.Context, name string) (*db.User, error) {
+	user := &db.User{}
+	var quotaStr, preferenceStr string
+	err := st.db.QueryRowContext(
+		ctx,
+		st.rebind(`select id, name, pwd, role, used_space, quota, preference
+		from t_user
+		where name=?`),
+		name,
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Pwd,
+		&user.Role,
+		&user.UsedSpace,
+		&quotaStr,
+		&preferenceStr,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, db.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(quotaStr), &user.Quota); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(preferenceStr), &user.Preferences); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (st *Store) SetPwd(ctx context.Context, id uint64, pwd string) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`update t_user
+			set pwd=?
+			where id=?`),
+			pwd,
+			id,
+		)
+		return err
+	})
+}
+
+// role + quota
+func (st *Store) SetInfo(ctx context.Context, id uint64, user *db.User) error {
+	quotaStr, err := json.Marshal(user.Quota)
+	if err != nil {
+		return err
+	}
+
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`update t_user
+			set role=?, quota=?
+			where id=?`),
+			user.Role, quotaStr,
+			id,
+		)
+		return err
+	})
+}
+
+func (st *Store) SetPreferences(ctx context.Context, id uint64, prefers *db.Preferences) error {
+	preferenceStr, err := json.Marshal(prefers)
+	if err != nil {
+		return err
+	}
+
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`update t_user
+			set preference=?
+			where id=?`),
+			preferenceStr,
+			id,
+		)
+		return err
+	})
+}
+
+// SetUsed runs its read-modify-write as a single transaction (submitted to
+// the Writer on SQLite; on Postgres the read locks the row with
+// `for update`), so the read of the current used_space and the following
+// update can no longer interleave with another writer's update of the same
+// row (the previous sync.RWMutex only serialized calls within this process,
+// not the read from the write).
+func (st *Store) SetUsed(ctx context.Context, id uint64, incr bool, capacity int64) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		gotUser, err := st.getUserTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if incr && gotUser.UsedSpace+capacity > int64(gotUser.Quota.SpaceLimit) {
+			return db.ErrReachedLimit
+		}
+
+		if incr {
+			gotUser.UsedSpace = gotUser.UsedSpace + capacity
+		} else {
+			if gotUser.UsedSpace-capacity < 0 {
+				return db.ErrNegtiveUsedSpace
+			}
+			gotUser.UsedSpace = gotUser.UsedSpace - capacity
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			st.rebind(`update t_user
+			set used_space=?
+			where id=?`),
+			gotUser.UsedSpace,
+			gotUser.ID,
+		)
+		return err
+	})
+}
+
+func (st *Store) ResetUsed(ctx context.Context, id uint64, used int64) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`update t_user
+			set used_space=?
+			where id=?`),
+			used,
+			id,
+		)
+		return err
+	})
+}
+
+// ListUsers loads every user in one shot. Kept for callers that don't care
+// about pagination; it just loops ListUsersPage until it runs dry, so it
+// still incurs the cost of an unbounded scan on large installations.
+func (st *Store) ListUsers(ctx context.Context) ([]*db.User, error) {
+	users := []*db.User{}
+	opts := ListUsersOpts{Limit: listUsersPageSize}
+	for {
+		page, next, err := st.ListUsersPage(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, page...)
+		if next == "" {
+			break
+		}
+		opts.Cursor = next
+	}
+	return users, nil
+}
+
+// ListUserIDs streams through pages rather than materializing every user at
+// once, since all it needs from each row is the name and id.
+func (st *Store) ListUserIDs(ctx context.Context) (map[string]string, error) {
+	nameToId := map[string]string{}
+	opts := ListUsersOpts{Limit: listUsersPageSize}
+	for {
+		page, next, err := st.ListUsersPage(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range page {
+			nameToId[user.Name] = fmt.Sprint(user.ID)
+		}
+		if next == "" {
+			break
+		}
+		opts.Cursor = next
+	}
+	return nameToId, nil
+}
@@ -0,0 +1,198 @@
+package rdbsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ihexxa/quickshare/src/db"
+)
+
+// listUsersPageSize is the page size ListUsers/ListUserIDs page through
+// internally when the caller didn't ask for pagination themselves.
+const listUsersPageSize = 256
+
+// ListUsersOpts controls a single ListUsersPage call. Limit defaults to
+// listUsersPageSize when unset.
+type ListUsersOpts struct {
+	Limit    int
+	Cursor   string
+	Role     string
+	NameLike string
+	SortBy   string // "id", "name" (default), or "used_space"
+	Order    string // "asc" (default) or "desc"
+}
+
+// userCursor is the decoded form of ListUsersOpts.Cursor: the sort column's
+// value and id of the last row in the previous page. Keying on (sort value,
+// id) rather than just id lets the cursor work no matter which column the
+// page is sorted by.
+type userCursor struct {
+	LastSortVal string `json:"last_sort_val"`
+	LastID      uint64 `json:"last_id"`
+}
+
+func encodeCursor(c userCursor) string {
+	b, _ := json.Marshal(c) // userCursor only has primitive fields, Marshal cannot fail
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (userCursor, error) {
+	var c userCursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return c, nil
+}
+
+var listUsersSortColumns = map[string]string{
+	"":           "name",
+	"name":       "name",
+	"id":         "id",
+	"used_space": "used_space",
+}
+
+// ListUsersPage returns one page of users, ordered and filtered per opts,
+// along with an opaque cursor for the next page ("" once there are no more
+// rows). It uses keyset pagination (`where (sort_col, id) > (?, ?)`) instead
+// of OFFSET, so paging through installations with thousands of users stays
+// O(limit) per page rather than O(offset).
+func (st *Store) ListUsersPage(ctx context.Context, opts ListUsersOpts) (users []*db.User, nextCursor string, err error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = listUsersPageSize
+	}
+
+	sortCol, ok := listUsersSortColumns[opts.SortBy]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown SortBy: %q", opts.SortBy)
+	}
+	order := "asc"
+	if strings.EqualFold(opts.Order, "desc") {
+		order = "desc"
+	}
+	cmp := ">"
+	if order == "desc" {
+		cmp = "<"
+	}
+
+	cursor, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := strings.Builder{}
+	query.WriteString(`select id, name, role, used_space, quota, preference from t_user where 1=1`)
+	args := []interface{}{}
+
+	if opts.Role != "" {
+		query.WriteString(` and role=?`)
+		args = append(args, opts.Role)
+	}
+	if opts.NameLike != "" {
+		query.WriteString(` and name like ?`)
+		args = append(args, opts.NameLike+"%")
+	}
+	if opts.Cursor != "" {
+		lastSortVal, err := sortValueArg(sortCol, cursor.LastSortVal)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor: %w", err)
+		}
+		fmt.Fprintf(&query, ` and (%s, id) %s (?, ?)`, sortCol, cmp)
+		args = append(args, lastSortVal, cursor.LastID)
+	}
+	fmt.Fprintf(&query, ` order by %s %s, id %s limit ?`, sortCol, order, order)
+	args = append(args, limit)
+
+	rows, err := st.db.QueryContext(ctx, st.rebind(query.String()), args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return []*db.User{}, "", nil
+		}
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	users = []*db.User{}
+	var lastSortVal string
+	for rows.Next() {
+		user := &db.User{}
+		var quotaStr, preferenceStr string
+		if err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Role,
+			&user.UsedSpace,
+			&quotaStr,
+			&preferenceStr,
+		); err != nil {
+			return nil, "", err
+		}
+		if err := json.Unmarshal([]byte(quotaStr), &user.Quota); err != nil {
+			return nil, "", err
+		}
+		if err := json.Unmarshal([]byte(preferenceStr), &user.Preferences); err != nil {
+			return nil, "", err
+		}
+
+		users = append(users, user)
+		lastSortVal = sortValue(sortCol, user)
+	}
+	if rows.Err() != nil {
+		return nil, "", rows.Err()
+	}
+
+	if len(users) == limit {
+		last := users[len(users)-1]
+		nextCursor = encodeCursor(userCursor{LastSortVal: lastSortVal, LastID: last.ID})
+	}
+	return users, nextCursor, nil
+}
+
+func sortValue(sortCol string, user *db.User) string {
+	switch sortCol {
+	case "id":
+		return strconv.FormatUint(user.ID, 10)
+	case "used_space":
+		return strconv.FormatInt(user.UsedSpace, 10)
+	default:
+		return user.Name
+	}
+}
+
+// sortValueArg converts a cursor's LastSortVal (always stored as a string,
+// since userCursor is shared across all three sort columns) back into the
+// type its column actually holds. SQLite's column-affinity coercion hides a
+// string/bigint mismatch here, but Postgres's "id"/"used_space" bigint
+// columns do not compare equal/ordered against a text literal, so the row
+// value comparison this function feeds would silently match nothing.
+func sortValueArg(sortCol, lastSortVal string) (interface{}, error) {
+	switch sortCol {
+	case "id":
+		v, err := strconv.ParseUint(lastSortVal, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	case "used_space":
+		v, err := strconv.ParseInt(lastSortVal, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return lastSortVal, nil
+	}
+}
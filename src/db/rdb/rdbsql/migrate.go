@@ -0,0 +1,288 @@
+package rdbsql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ihexxa/quickshare/src/db"
+)
+
+// Migration is one ordered, versioned step in the schema's history. Stmts run
+// inside a single transaction, so a migration either fully applies or not at
+// all.
+type Migration struct {
+	Version int
+	Name    string
+	Stmts   []string
+}
+
+func (m Migration) checksum() string {
+	h := sha256.New()
+	for _, stmt := range m.Stmts {
+		h.Write([]byte(stmt))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// migrations is the ordered schema history, per flavor. Flavors diverge on
+// auto-increment syntax and JSON column types, so each keeps its own
+// statements rather than a lowest-common-denominator schema. Never edit a
+// migration that has already shipped: append a new one instead, so installs
+// on any prior version upgrade cleanly.
+var migrations = map[Flavor][]Migration{
+	FlavorSQLite: {
+		{
+			Version: 1,
+			Name:    "create t_user",
+			Stmts: []string{
+				`create table if not exists t_user (
+					id integer primary key,
+					name text not null unique,
+					pwd text not null,
+					role text not null,
+					used_space integer not null,
+					quota text not null,
+					preference text not null
+				)`,
+			},
+		},
+		{
+			Version: 2,
+			Name:    "create roles and permissions, seed built-in roles",
+			Stmts: append([]string{
+				`create table if not exists t_role (
+					name text primary key,
+					description text not null,
+					created_at integer not null
+				)`,
+				`create table if not exists t_role_permission (
+					role text not null,
+					permission text not null,
+					primary key (role, permission)
+				)`,
+			}, seedRoleStmts()...),
+		},
+		{
+			Version: 3,
+			Name:    "create t_webauthn_credential",
+			Stmts: []string{
+				`create table if not exists t_webauthn_credential (
+					credential_id blob primary key,
+					user_id integer not null,
+					public_key blob not null,
+					attestation_type text not null,
+					aaguid blob not null,
+					sign_count integer not null,
+					clone_warning integer not null,
+					transports text not null,
+					created_at integer not null
+				)`,
+			},
+		},
+	},
+	FlavorPostgres: {
+		{
+			Version: 1,
+			Name:    "create t_user",
+			Stmts: []string{
+				`create table if not exists t_user (
+					id bigint primary key,
+					name text not null unique,
+					pwd text not null,
+					role text not null,
+					used_space bigint not null,
+					quota jsonb not null,
+					preference jsonb not null
+				)`,
+			},
+		},
+		{
+			Version: 2,
+			Name:    "create roles and permissions, seed built-in roles",
+			Stmts: append([]string{
+				`create table if not exists t_role (
+					name text primary key,
+					description text not null,
+					created_at bigint not null
+				)`,
+				`create table if not exists t_role_permission (
+					role text not null,
+					permission text not null,
+					primary key (role, permission)
+				)`,
+			}, seedRoleStmts()...),
+		},
+		{
+			Version: 3,
+			Name:    "create t_webauthn_credential",
+			Stmts: []string{
+				`create table if not exists t_webauthn_credential (
+					credential_id bytea primary key,
+					user_id bigint not null,
+					public_key bytea not null,
+					attestation_type text not null,
+					aaguid bytea not null,
+					sign_count bigint not null,
+					clone_warning boolean not null,
+					transports jsonb not null,
+					created_at bigint not null
+				)`,
+			},
+		},
+	},
+}
+
+// seedRoleStmts seeds the three built-in roles quickshare has always had, so
+// existing installs upgrading to v2 keep working with the HTTP middleware
+// (handlers.RequirePermission), which consults this table, and with
+// db.CheckUser once it is switched over from its hard-coded role checks.
+func seedRoleStmts() []string {
+	stmts := []string{
+		fmt.Sprintf(`insert into t_role (name, description, created_at) values ('%s', 'full administrative access', 0)`, db.AdminRole),
+		fmt.Sprintf(`insert into t_role (name, description, created_at) values ('%s', 'regular authenticated user', 0)`, db.UserRole),
+		fmt.Sprintf(`insert into t_role (name, description, created_at) values ('%s', 'unauthenticated visitor', 0)`, db.VisitorRole),
+	}
+
+	grants := []struct {
+		role  string
+		perms []string
+	}{
+		{db.AdminRole, []string{PermUsersAdmin, PermFilesUpload, PermFilesShare}},
+		{db.UserRole, []string{PermFilesUpload, PermFilesShare}},
+	}
+	for _, g := range grants {
+		for _, perm := range g.perms {
+			stmts = append(stmts, fmt.Sprintf(
+				`insert into t_role_permission (role, permission) values ('%s', '%s')`,
+				g.role, perm,
+			))
+		}
+	}
+	return stmts
+}
+
+var migrationsTableDDL = map[Flavor]string{
+	FlavorSQLite: `create table if not exists t_migrations (
+		id integer primary key,
+		version integer not null unique,
+		applied_at integer not null,
+		checksum text not null
+	)`,
+	FlavorPostgres: `create table if not exists t_migrations (
+		id bigserial primary key,
+		version integer not null unique,
+		applied_at bigint not null,
+		checksum text not null
+	)`,
+}
+
+// init brings a fresh or existing database up to the latest schema version.
+func (st *Store) init() error {
+	return st.Migrate(context.Background())
+}
+
+// Migrate runs every migration newer than the database's current version,
+// each inside its own transaction. A failure rolls back that migration and
+// aborts startup, leaving the database at the last good version. Open()
+// calls it through init, so the cmd package's `--migrate-only` flag runs it
+// by opening the store and then exits before starting the server.
+func (st *Store) Migrate(ctx context.Context) error {
+	if _, err := st.db.ExecContext(ctx, migrationsTableDDL[st.flavor]); err != nil {
+		return err
+	}
+
+	applied, err := st.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	maxVersion := 0
+	for _, m := range migrations[st.flavor] {
+		if m.Version > maxVersion {
+			maxVersion = m.Version
+		}
+		if applied[m.Version] {
+			continue
+		}
+		if err := st.applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("migration v%d (%s) failed: %w", m.Version, m.Name, err)
+		}
+	}
+
+	// t_migrations is the source of truth for what's applied, but SQLite's
+	// own PRAGMA user_version is the standard place tooling (and the
+	// integration tests) expect to find the current schema version, so keep
+	// it in sync too.
+	if st.flavor == FlavorSQLite {
+		if _, err := st.db.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, maxVersion)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (st *Store) appliedVersions(ctx context.Context) (map[int]bool, error) {
+	rows, err := st.db.QueryContext(ctx, `select version from t_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func (st *Store) applyMigration(ctx context.Context, m Migration) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		for _, stmt := range m.Stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`insert into t_migrations (version, applied_at, checksum) values (?, ?, ?)`),
+			m.Version,
+			time.Now().Unix(),
+			m.checksum(),
+		)
+		return err
+	})
+}
+
+// rebind rewrites `?` placeholders into the target flavor's native form.
+// SQLite accepts `?` as-is; Postgres requires positional `$N` placeholders.
+func (st *Store) rebind(query string) string {
+	if st.flavor != FlavorPostgres {
+		return query
+	}
+
+	var buf strings.Builder
+	buf.Grow(len(query) + 8)
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			buf.WriteByte('$')
+			buf.WriteString(strconv.Itoa(n))
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,46 @@
+package rdbsql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrateFreshDB(t *testing.T) {
+	st, err := (&SQLite{File: ":memory:"}).Open()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	sqlSt := st.(*Store)
+	ctx := context.Background()
+
+	applied, err := sqlSt.appliedVersions(ctx)
+	if err != nil {
+		t.Fatalf("appliedVersions: %v", err)
+	}
+	if !applied[1] {
+		t.Fatalf("expected v1 to be recorded as applied, got %v", applied)
+	}
+
+	// re-running migrate on an already-migrated DB must be a no-op
+	if err := sqlSt.Migrate(ctx); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+
+	var count int
+	row := sqlSt.db.QueryRowContext(ctx, `select count(*) from t_migrations`)
+	if err := row.Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if want := len(migrations[sqlSt.flavor]); count != want {
+		t.Fatalf("expected %d migration rows, got %d", want, count)
+	}
+
+	var userVersion int
+	if err := sqlSt.db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&userVersion); err != nil {
+		t.Fatalf("PRAGMA user_version: %v", err)
+	}
+	if want := len(migrations[sqlSt.flavor]); userVersion != want {
+		t.Fatalf("expected PRAGMA user_version = %d, got %d", want, userVersion)
+	}
+}
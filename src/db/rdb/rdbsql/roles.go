@@ -0,0 +1,146 @@
+package rdbsql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// t_role / t_role_permission back the role/permission storage here, and
+// src/handlers.RequirePermission is the enforcement side: it consults
+// RoleHasPermission directly, so a grant or revoke through this file changes
+// what a request wrapped in that middleware is allowed to do. What's still
+// missing is an auth layer that resolves a request's caller and sets
+// src/handlers.RoleContextKey before RequirePermission runs; db.CheckUser's
+// hard-coded AdminRole/UserRole/VisitorRole branch is unaffected by this
+// file until something switches it to call RoleHasPermission too.
+
+// Permission is a string constant naming one grantable action. Keeping
+// permissions as plain strings (rather than an enum) lets new ones ship in a
+// migration without a code change to a closed type.
+const (
+	PermFilesUpload = "files.upload"
+	PermFilesShare  = "files.share"
+	PermUsersAdmin  = "users.admin"
+)
+
+// AddRole creates a role with no permissions. Granting permissions is a
+// separate call so callers can build up a role incrementally.
+//
+// AddRole, DelRole, and ListRoles keep db.IUserStore's existing
+// non-context signature rather than taking ctx like the rest of Store;
+// unlike GrantPermission and friends below, they're not new surface, they
+// implement an interface method this type has had since before this file
+// existed.
+func (st *Store) AddRole(role string) error {
+	return st.write(context.Background(), func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			context.Background(),
+			st.rebind(`insert into t_role (name, description, created_at) values (?, ?, ?)`),
+			role, "", time.Now().Unix(),
+		)
+		return err
+	})
+}
+
+// DelRole removes a role and every permission granted to it.
+func (st *Store) DelRole(role string) error {
+	return st.write(context.Background(), func(tx *sql.Tx) error {
+		ctx := context.Background()
+		if _, err := tx.ExecContext(ctx, st.rebind(`delete from t_role_permission where role=?`), role); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, st.rebind(`delete from t_role where name=?`), role)
+		return err
+	})
+}
+
+// ListRoles returns every known role name, keyed so callers can do an O(1)
+// membership check the same way the old hard-coded role checks did.
+func (st *Store) ListRoles() (map[string]bool, error) {
+	rows, err := st.db.QueryContext(context.Background(), `select name from t_role`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		roles[name] = true
+	}
+	return roles, rows.Err()
+}
+
+// GrantPermission gives role the named permission. It is a no-op (not an
+// error) if the role already has it.
+func (st *Store) GrantPermission(ctx context.Context, role, perm string) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(insertRolePermission[st.flavor]),
+			role, perm,
+		)
+		return err
+	})
+}
+
+// RevokePermission removes perm from role, if it was granted.
+func (st *Store) RevokePermission(ctx context.Context, role, perm string) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`delete from t_role_permission where role=? and permission=?`),
+			role, perm,
+		)
+		return err
+	})
+}
+
+// RoleHasPermission reports whether role has been granted perm.
+func (st *Store) RoleHasPermission(ctx context.Context, role, perm string) (bool, error) {
+	var count int
+	err := st.db.QueryRowContext(
+		ctx,
+		st.rebind(`select count(*) from t_role_permission where role=? and permission=?`),
+		role, perm,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListPermissions returns every permission granted to role.
+func (st *Store) ListPermissions(ctx context.Context, role string) ([]string, error) {
+	rows, err := st.db.QueryContext(
+		ctx,
+		st.rebind(`select permission from t_role_permission where role=?`),
+		role,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := []string{}
+	for rows.Next() {
+		var perm string
+		if err := rows.Scan(&perm); err != nil {
+			return nil, err
+		}
+		perms = append(perms, perm)
+	}
+	return perms, rows.Err()
+}
+
+// insertRolePermission upserts a (role, permission) grant. SQLite and
+// Postgres spell "ignore if it already exists" differently, so each flavor
+// gets its own statement.
+var insertRolePermission = map[Flavor]string{
+	FlavorSQLite:   `insert or ignore into t_role_permission (role, permission) values (?, ?)`,
+	FlavorPostgres: `insert into t_role_permission (role, permission) values (?, ?) on conflict do nothing`,
+}
@@ -0,0 +1,70 @@
+package rdbsql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ihexxa/quickshare/src/db"
+)
+
+func TestBuiltinRolesSeededOnMigrate(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	roles, err := st.ListRoles()
+	if err != nil {
+		t.Fatalf("ListRoles: %v", err)
+	}
+	for _, want := range []string{db.AdminRole, db.UserRole, db.VisitorRole} {
+		if !roles[want] {
+			t.Fatalf("expected built-in role %q to be seeded, got %v", want, roles)
+		}
+	}
+
+	hasAdmin, err := st.RoleHasPermission(ctx, db.AdminRole, PermUsersAdmin)
+	if err != nil {
+		t.Fatalf("RoleHasPermission: %v", err)
+	}
+	if !hasAdmin {
+		t.Fatalf("expected %q to have %q", db.AdminRole, PermUsersAdmin)
+	}
+
+	hasVisitor, err := st.RoleHasPermission(ctx, db.VisitorRole, PermUsersAdmin)
+	if err != nil {
+		t.Fatalf("RoleHasPermission: %v", err)
+	}
+	if hasVisitor {
+		t.Fatalf("visitor should not have %q", PermUsersAdmin)
+	}
+}
+
+func TestGrantRevokePermission(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	if err := st.AddRole("editor"); err != nil {
+		t.Fatalf("AddRole: %v", err)
+	}
+	if err := st.GrantPermission(ctx, "editor", PermFilesShare); err != nil {
+		t.Fatalf("GrantPermission: %v", err)
+	}
+
+	ok, err := st.RoleHasPermission(ctx, "editor", PermFilesShare)
+	if err != nil {
+		t.Fatalf("RoleHasPermission: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected editor to have %q after grant", PermFilesShare)
+	}
+
+	if err := st.RevokePermission(ctx, "editor", PermFilesShare); err != nil {
+		t.Fatalf("RevokePermission: %v", err)
+	}
+	ok, err = st.RoleHasPermission(ctx, "editor", PermFilesShare)
+	if err != nil {
+		t.Fatalf("RoleHasPermission: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected editor to lose %q after revoke", PermFilesShare)
+	}
+}
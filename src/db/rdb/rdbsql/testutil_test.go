@@ -0,0 +1,15 @@
+package rdbsql
+
+import "testing"
+
+// newTestStore opens a fresh in-memory SQLite store, migrated to the latest
+// schema version, for use by this package's tests.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	rawSt, err := (&SQLite{File: ":memory:"}).Open()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return rawSt.(*Store)
+}
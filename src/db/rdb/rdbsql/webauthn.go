@@ -0,0 +1,153 @@
+package rdbsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// This file is the credential store only: the registration/assertion
+// ceremony itself — the /v2/users/self/webauthn/register/{begin,finish}
+// and login/{begin,finish} HTTP handlers, and the in-memory challenge
+// storage they use — lives in src/handlers/webauthn.go on top of this store.
+
+// Credential mirrors the go-webauthn library's webauthn.Credential, so a
+// handler can round-trip one through the store without extra translation.
+type Credential struct {
+	CredentialID    []byte
+	UserID          uint64
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	CloneWarning    bool
+	Transports      []string
+	CreatedAt       int64
+}
+
+var ErrCredentialNotFound = errors.New("webauthn credential not found")
+
+// AddCredential stores a newly-registered credential.
+func (st *Store) AddCredential(ctx context.Context, cred *Credential) error {
+	transportsStr, err := json.Marshal(cred.Transports)
+	if err != nil {
+		return err
+	}
+
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`insert into t_webauthn_credential
+			(credential_id, user_id, public_key, attestation_type, aaguid, sign_count, clone_warning, transports, created_at)
+			values (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			cred.CredentialID,
+			cred.UserID,
+			cred.PublicKey,
+			cred.AttestationType,
+			cred.AAGUID,
+			cred.SignCount,
+			cred.CloneWarning,
+			transportsStr,
+			cred.CreatedAt,
+		)
+		return err
+	})
+}
+
+// GetCredentialsByUser returns every credential a user has registered, which
+// the login ceremony offers as allowed credentials.
+func (st *Store) GetCredentialsByUser(ctx context.Context, userID uint64) ([]*Credential, error) {
+	rows, err := st.db.QueryContext(
+		ctx,
+		st.rebind(`select credential_id, user_id, public_key, attestation_type, aaguid, sign_count, clone_warning, transports, created_at
+		from t_webauthn_credential
+		where user_id=?`),
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := []*Credential{}
+	for rows.Next() {
+		cred, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, cred)
+	}
+	return creds, rows.Err()
+}
+
+// GetCredentialByID looks up a single credential by its id, which is what
+// the assertion response identifies itself with during login.
+func (st *Store) GetCredentialByID(ctx context.Context, credentialID []byte) (*Credential, error) {
+	row := st.db.QueryRowContext(
+		ctx,
+		st.rebind(`select credential_id, user_id, public_key, attestation_type, aaguid, sign_count, clone_warning, transports, created_at
+		from t_webauthn_credential
+		where credential_id=?`),
+		credentialID,
+	)
+	cred, err := scanCredential(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, err
+	}
+	return cred, nil
+}
+
+// DelCredential removes a credential, e.g. when a user revokes a key.
+func (st *Store) DelCredential(ctx context.Context, credentialID []byte) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, st.rebind(`delete from t_webauthn_credential where credential_id=?`), credentialID)
+		return err
+	})
+}
+
+// UpdateSignCount persists the authenticator's new signature counter after a
+// successful login, so a future login can detect a cloned authenticator via
+// a counter that goes backwards.
+func (st *Store) UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) error {
+	return st.write(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			st.rebind(`update t_webauthn_credential set sign_count=? where credential_id=?`),
+			signCount,
+			credentialID,
+		)
+		return err
+	})
+}
+
+// rowScanner lets scanCredential work against both *sql.Rows and *sql.Row.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCredential(row rowScanner) (*Credential, error) {
+	cred := &Credential{}
+	var transportsStr string
+	err := row.Scan(
+		&cred.CredentialID,
+		&cred.UserID,
+		&cred.PublicKey,
+		&cred.AttestationType,
+		&cred.AAGUID,
+		&cred.SignCount,
+		&cred.CloneWarning,
+		&transportsStr,
+		&cred.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(transportsStr), &cred.Transports); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
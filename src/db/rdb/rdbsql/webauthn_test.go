@@ -0,0 +1,60 @@
+package rdbsql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCredentialRoundTrip(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	cred := &Credential{
+		CredentialID:    []byte("cred-1"),
+		UserID:          1,
+		PublicKey:       []byte("pubkey"),
+		AttestationType: "none",
+		AAGUID:          []byte("aaguid"),
+		SignCount:       0,
+		CloneWarning:    false,
+		Transports:      []string{"usb", "nfc"},
+		CreatedAt:       1,
+	}
+	if err := st.AddCredential(ctx, cred); err != nil {
+		t.Fatalf("AddCredential: %v", err)
+	}
+
+	got, err := st.GetCredentialByID(ctx, []byte("cred-1"))
+	if err != nil {
+		t.Fatalf("GetCredentialByID: %v", err)
+	}
+	if got.UserID != 1 || got.AttestationType != "none" || len(got.Transports) != 2 {
+		t.Fatalf("unexpected credential: %+v", got)
+	}
+
+	if err := st.UpdateSignCount(ctx, []byte("cred-1"), 5); err != nil {
+		t.Fatalf("UpdateSignCount: %v", err)
+	}
+	got, err = st.GetCredentialByID(ctx, []byte("cred-1"))
+	if err != nil {
+		t.Fatalf("GetCredentialByID after update: %v", err)
+	}
+	if got.SignCount != 5 {
+		t.Fatalf("expected sign count 5, got %d", got.SignCount)
+	}
+
+	byUser, err := st.GetCredentialsByUser(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetCredentialsByUser: %v", err)
+	}
+	if len(byUser) != 1 {
+		t.Fatalf("expected 1 credential for user, got %d", len(byUser))
+	}
+
+	if err := st.DelCredential(ctx, []byte("cred-1")); err != nil {
+		t.Fatalf("DelCredential: %v", err)
+	}
+	if _, err := st.GetCredentialByID(ctx, []byte("cred-1")); err != ErrCredentialNotFound {
+		t.Fatalf("expected ErrCredentialNotFound, got %v", err)
+	}
+}
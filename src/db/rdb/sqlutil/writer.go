@@ -0,0 +1,132 @@
+// Package sqlutil holds helpers shared across quickshare's SQL-backed
+// stores that aren't specific to any one flavor.
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// job is one closure submitted to a Writer, paired with where to send its
+// result.
+type job struct {
+	ctx  context.Context
+	fn   func(*sql.Tx) error
+	done chan error
+}
+
+// Writer serializes writes to a *sql.DB through a single goroutine. SQLite
+// only allows one writer at a time at the file level; interleaving
+// independent read-modify-write calls under a Go-level mutex (the prior
+// approach) still left a gap between a store's "read" and its "write",
+// and didn't compose across the separate users/files/sharings stores that
+// all write to the same file. Submitting a closure here instead makes the
+// whole read-modify-write sequence atomic and FIFO-ordered.
+type Writer struct {
+	db      *sql.DB
+	jobs    chan job
+	closed  chan struct{}
+	retries int
+	backoff time.Duration
+}
+
+// NewWriter starts the Writer's goroutine and returns once it is ready to
+// accept submissions. Call Close to stop it.
+func NewWriter(db *sql.DB) *Writer {
+	w := &Writer{
+		db:      db,
+		jobs:    make(chan job),
+		closed:  make(chan struct{}),
+		retries: 5,
+		backoff: 20 * time.Millisecond,
+	}
+	go w.run()
+	return w
+}
+
+// Close stops accepting new submissions. In-flight submissions already sent
+// to Submit still complete.
+func (w *Writer) Close() {
+	close(w.closed)
+}
+
+// Submit runs fn inside a transaction on the Writer's goroutine and blocks
+// until it commits, rolls back, or ctx is canceled. Submissions are
+// processed strictly in the order they arrive.
+func (w *Writer) Submit(ctx context.Context, fn func(*sql.Tx) error) error {
+	j := job{ctx: ctx, fn: fn, done: make(chan error, 1)}
+
+	select {
+	case w.jobs <- j:
+	case <-w.closed:
+		return errors.New("sqlutil: writer is closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Writer) run() {
+	for {
+		select {
+		case j := <-w.jobs:
+			j.done <- w.execWithRetry(j)
+		case <-w.closed:
+			return
+		}
+	}
+}
+
+func (w *Writer) execWithRetry(j job) error {
+	if err := j.ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	wait := w.backoff
+	for attempt := 0; attempt <= w.retries; attempt++ {
+		err = w.exec(j)
+		if err == nil || !isBusy(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-j.ctx.Done():
+			// The caller gave up; don't hold the FIFO queue hostage
+			// retrying a job nobody is waiting on anymore.
+			return j.ctx.Err()
+		}
+		wait *= 2
+	}
+	return err
+}
+
+func (w *Writer) exec(j job) error {
+	tx, err := w.db.BeginTx(j.ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := j.fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// isBusy reports whether err came from SQLite reporting the database file
+// is locked by another writer, which is the one case worth retrying.
+func isBusy(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
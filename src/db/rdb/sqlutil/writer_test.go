@@ -0,0 +1,105 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestWriterSerializesSubmissions(t *testing.T) {
+	rawDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := rawDB.Exec(`create table t_counter (n integer not null)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if _, err := rawDB.Exec(`insert into t_counter (n) values (0)`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	w := NewWriter(rawDB)
+	defer w.Close()
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := w.Submit(context.Background(), func(tx *sql.Tx) error {
+				var cur int
+				if err := tx.QueryRow(`select n from t_counter`).Scan(&cur); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`update t_counter set n=?`, cur+1)
+				return err
+			})
+			if err != nil {
+				t.Errorf("submit: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var got int
+	if err := rawDB.QueryRow(`select n from t_counter`).Scan(&got); err != nil {
+		t.Fatalf("final read: %v", err)
+	}
+	if got != n {
+		t.Fatalf("expected %d, got %d (a read-modify-write race slipped through)", n, got)
+	}
+}
+
+// busyErr behaves enough like a SQLite "database is locked" error to drive
+// isBusy's retry path without needing to actually contend on a file lock.
+type busyErr struct{}
+
+func (busyErr) Error() string { return "database is locked" }
+
+// TestCanceledJobDoesNotBlockQueue makes sure a job stuck retrying on
+// SQLITE_BUSY stops holding the single writer goroutine as soon as its own
+// caller gives up, so the job behind it in the FIFO queue isn't stuck
+// waiting out someone else's backoff.
+func TestCanceledJobDoesNotBlockQueue(t *testing.T) {
+	rawDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	w := NewWriter(rawDB)
+	w.backoff = time.Hour // job A would otherwise occupy the writer for hours of doubling retry waits
+	defer w.Close()
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	doneA := make(chan error, 1)
+	go func() {
+		doneA <- w.Submit(ctxA, func(tx *sql.Tx) error {
+			close(started)
+			return busyErr{}
+		})
+	}()
+
+	<-started
+	cancelA()
+
+	start := time.Now()
+	err = w.Submit(context.Background(), func(tx *sql.Tx) error { return nil })
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("job B: %v", err)
+	}
+	if elapsed > time.Second {
+		t.Fatalf("job B took %s behind a canceled job A; a canceled job should stop retrying instead of occupying the writer", elapsed)
+	}
+
+	if aErr := <-doneA; !errors.Is(aErr, context.Canceled) {
+		t.Fatalf("expected job A to return context.Canceled, got %v", aErr)
+	}
+}
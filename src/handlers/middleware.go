@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ihexxa/quickshare/src/db/rdb/rdbsql"
+)
+
+// roleContextKey is the context key an authentication layer must set before
+// RequirePermission runs. The key value is exported as RoleContextKey so
+// that layer (not part of this tree) can set it without naming this
+// unexported type.
+type roleContextKey struct{}
+
+// RoleContextKey is the context.Context key holding the authenticated
+// request's role, as a string matching one of the role names RoleHasPermission
+// checks against (e.g. db.AdminRole). Whatever resolves the caller's identity
+// - a session cookie, a JWT, db.CheckUser's real implementation - must set
+// it before the request reaches a RequirePermission-wrapped handler.
+var RoleContextKey = roleContextKey{}
+
+// RequirePermission returns middleware that 403s any request whose role
+// (read from RoleContextKey) hasn't been granted perm in store, and calls
+// next otherwise. This is the enforcement side of the role/permission
+// storage in package rdbsql: granting or revoking a permission
+// through Store.GrantPermission/RevokePermission now changes what a request
+// wrapped in this middleware is allowed to do.
+func RequirePermission(store *rdbsql.Store, perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value(RoleContextKey).(string)
+			if role == "" {
+				http.Error(w, "no authenticated role on request", http.StatusUnauthorized)
+				return
+			}
+
+			ok, err := store.RoleHasPermission(r.Context(), role, perm)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithRole returns a copy of ctx carrying role under RoleContextKey, for
+// callers (tests, or the eventual auth layer) that need to set it.
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, RoleContextKey, role)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ihexxa/quickshare/src/db/rdb/rdbsql"
+)
+
+func newTestStore(t *testing.T) *rdbsql.Store {
+	t.Helper()
+	rawSt, err := (&rdbsql.SQLite{File: ":memory:"}).Open()
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	return rawSt.(*rdbsql.Store)
+}
+
+func TestRequirePermissionDeniesWithoutRole(t *testing.T) {
+	st := newTestStore(t)
+	called := false
+	mw := RequirePermission(st, rdbsql.PermUsersAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	mw.ServeHTTP(w, req)
+
+	if called {
+		t.Fatal("next handler ran for a request with no role on its context")
+	}
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestRequirePermissionReflectsGrantAndRevoke(t *testing.T) {
+	st := newTestStore(t)
+	ctx := WithRole(context.Background(), "editor")
+	if err := st.AddRole("editor"); err != nil {
+		t.Fatalf("AddRole: %v", err)
+	}
+
+	called := false
+	mw := RequirePermission(st, rdbsql.PermFilesShare)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	// No grant yet: denied.
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	mw.ServeHTTP(w, req)
+	if called || w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 before granting, got %d (called=%v)", w.Code, called)
+	}
+
+	// Grant the permission: allowed.
+	if err := st.GrantPermission(ctx, "editor", rdbsql.PermFilesShare); err != nil {
+		t.Fatalf("GrantPermission: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w = httptest.NewRecorder()
+	mw.ServeHTTP(w, req)
+	if !called || w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after granting, got %d (called=%v)", w.Code, called)
+	}
+
+	// Revoke it: denied again.
+	if err := st.RevokePermission(ctx, "editor", rdbsql.PermFilesShare); err != nil {
+		t.Fatalf("RevokePermission: %v", err)
+	}
+	called = false
+	req = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w = httptest.NewRecorder()
+	mw.ServeHTTP(w, req)
+	if called || w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 after revoking, got %d (called=%v)", w.Code, called)
+	}
+}
@@ -0,0 +1,308 @@
+// Package handlers holds the HTTP ceremony endpoints that sit on top of the
+// credential store in src/db/rdb/rdbsql: the register/begin, register/finish,
+// login/begin, and login/finish handlers a router would mount at
+// /v2/users/self/webauthn/....
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/ihexxa/quickshare/src/db"
+	"github.com/ihexxa/quickshare/src/db/rdb/rdbsql"
+)
+
+// sessionTTL bounds how long a begin/finish pair has to complete a ceremony.
+const sessionTTL = 5 * time.Minute
+
+// webauthnUserContextKey is the context key an authentication layer must set
+// to the caller's own username before a request reaches RegisterBegin or
+// RegisterFinish. Registering a credential enrolls it for future login, so
+// it must only ever happen for the requester's own account; unlike those two,
+// LoginBegin/LoginFinish legitimately take the target name from the request
+// (LoginBegin's JSON body, LoginFinish's "name" query parameter), since the
+// whole point of the login ceremony is authenticating a
+// caller who doesn't have a session yet.
+type webauthnUserContextKey struct{}
+
+// WebauthnUserContextKey is the context.Context key RegisterBegin and
+// RegisterFinish read the authenticated caller's username from. Whatever
+// resolves the caller's identity - a session cookie, a JWT - must set it
+// before the request reaches these handlers, the same way handlers.RoleContextKey
+// is set before a RequirePermission-wrapped handler runs.
+var WebauthnUserContextKey = webauthnUserContextKey{}
+
+// WithWebauthnUser returns a copy of ctx carrying name under
+// WebauthnUserContextKey, for callers (tests, or the eventual auth layer)
+// that need to set it.
+func WithWebauthnUser(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, WebauthnUserContextKey, name)
+}
+
+// WebauthnHandlers drives the registration and assertion ceremonies against
+// a credential Store. It keeps in-flight ceremony challenges in memory
+// keyed by user ID, which is only correct behind a single server instance;
+// a multi-instance deployment would need to move sessions into something
+// shared (e.g. the Store itself, or a cache) instead.
+type WebauthnHandlers struct {
+	wa    *webauthn.WebAuthn
+	store *rdbsql.Store
+
+	mu       sync.Mutex
+	sessions map[uint64]sessionEntry
+}
+
+type sessionEntry struct {
+	data    webauthn.SessionData
+	expires time.Time
+}
+
+// NewWebauthnHandlers builds handlers against wa (the relying-party config)
+// and store (where credentials and the built-in user accounts live).
+func NewWebauthnHandlers(wa *webauthn.WebAuthn, store *rdbsql.Store) *WebauthnHandlers {
+	return &WebauthnHandlers{
+		wa:       wa,
+		store:    store,
+		sessions: map[uint64]sessionEntry{},
+	}
+}
+
+func (h *WebauthnHandlers) putSession(userID uint64, data *webauthn.SessionData) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions[userID] = sessionEntry{data: *data, expires: time.Now().Add(sessionTTL)}
+}
+
+func (h *WebauthnHandlers) takeSession(userID uint64) (webauthn.SessionData, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.sessions[userID]
+	delete(h.sessions, userID)
+	if !ok {
+		return webauthn.SessionData{}, fmt.Errorf("no in-progress webauthn ceremony for this user")
+	}
+	if time.Now().After(entry.expires) {
+		return webauthn.SessionData{}, fmt.Errorf("webauthn ceremony expired, start again")
+	}
+	return entry.data, nil
+}
+
+// webauthnUser adapts a db.User plus its stored credentials to the
+// go-webauthn library's User interface.
+type webauthnUser struct {
+	user  *db.User
+	creds []*rdbsql.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte          { return []byte(strconv.FormatUint(u.user.ID, 10)) }
+func (u *webauthnUser) WebAuthnName() string        { return u.user.Name }
+func (u *webauthnUser) WebAuthnDisplayName() string { return u.user.Name }
+func (u *webauthnUser) WebAuthnIcon() string        { return "" }
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, len(u.creds))
+	for i, c := range u.creds {
+		creds[i] = toLibCredential(c)
+	}
+	return creds
+}
+
+func toLibCredential(c *rdbsql.Credential) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, len(c.Transports))
+	for i, t := range c.Transports {
+		transports[i] = protocol.AuthenticatorTransport(t)
+	}
+	return webauthn.Credential{
+		ID:              c.CredentialID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       c.AAGUID,
+			SignCount:    c.SignCount,
+			CloneWarning: c.CloneWarning,
+		},
+	}
+}
+
+func fromLibCredential(userID uint64, c *webauthn.Credential) *rdbsql.Credential {
+	transports := make([]string, len(c.Transport))
+	for i, t := range c.Transport {
+		transports[i] = string(t)
+	}
+	return &rdbsql.Credential{
+		CredentialID:    c.ID,
+		UserID:          userID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		AAGUID:          c.Authenticator.AAGUID,
+		SignCount:       c.Authenticator.SignCount,
+		CloneWarning:    c.Authenticator.CloneWarning,
+		Transports:      transports,
+		CreatedAt:       time.Now().Unix(),
+	}
+}
+
+func (h *WebauthnHandlers) webauthnUserByName(r *http.Request, name string) (*webauthnUser, error) {
+	ctx := r.Context()
+	user, err := h.store.GetUserByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := h.store.GetCredentialsByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &webauthnUser{user: user, creds: creds}, nil
+}
+
+type beginRequest struct {
+	Name string `json:"name"`
+}
+
+// RegisterBegin starts a credential registration ceremony for the
+// authenticated caller and returns the PublicKeyCredentialCreationOptions
+// the browser's navigator.credentials.create() call needs.
+//
+// The target username comes from WebauthnUserContextKey, not the request
+// body: registering a credential is only ever valid for the caller's own
+// account, so a router wiring this in must resolve the authenticated user
+// and set it with WithWebauthnUser before the request reaches here.
+func (h *WebauthnHandlers) RegisterBegin(w http.ResponseWriter, r *http.Request) {
+	name, _ := r.Context().Value(WebauthnUserContextKey).(string)
+	if name == "" {
+		http.Error(w, "no authenticated user on request", http.StatusUnauthorized)
+		return
+	}
+
+	wu, err := h.webauthnUserByName(r, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	creation, session, err := h.wa.BeginRegistration(wu)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.putSession(wu.user.ID, session)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(creation)
+}
+
+// RegisterFinish validates the browser's attestation response against the
+// challenge started by RegisterBegin and stores the resulting credential.
+// As with RegisterBegin, the target username comes from the authenticated
+// session, not the request, so a ceremony begun for one account can't be
+// finished against another.
+func (h *WebauthnHandlers) RegisterFinish(w http.ResponseWriter, r *http.Request) {
+	name, _ := r.Context().Value(WebauthnUserContextKey).(string)
+	if name == "" {
+		http.Error(w, "no authenticated user on request", http.StatusUnauthorized)
+		return
+	}
+
+	wu, err := h.webauthnUserByName(r, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	session, err := h.takeSession(wu.user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cred, err := h.wa.FinishRegistration(wu, session, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.AddCredential(r.Context(), fromLibCredential(wu.user.ID, cred)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// LoginBegin starts an assertion ceremony for the named user and returns
+// the PublicKeyCredentialRequestOptions the browser's
+// navigator.credentials.get() call needs.
+func (h *WebauthnHandlers) LoginBegin(w http.ResponseWriter, r *http.Request) {
+	var req beginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wu, err := h.webauthnUserByName(r, req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if len(wu.creds) == 0 {
+		http.Error(w, "no registered credential for this user", http.StatusBadRequest)
+		return
+	}
+
+	assertion, session, err := h.wa.BeginLogin(wu)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.putSession(wu.user.ID, session)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assertion)
+}
+
+// LoginFinish validates the browser's assertion response against the
+// challenge started by LoginBegin and persists the authenticator's updated
+// signature counter.
+func (h *WebauthnHandlers) LoginFinish(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	wu, err := h.webauthnUserByName(r, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	session, err := h.takeSession(wu.user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cred, err := h.wa.FinishLogin(wu, session, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.UpdateSignCount(r.Context(), cred.ID, cred.Authenticator.SignCount); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RegisterRoutes mounts the four ceremony endpoints on mux.
+func (h *WebauthnHandlers) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/v2/users/self/webauthn/register/begin", h.RegisterBegin)
+	mux.HandleFunc("/v2/users/self/webauthn/register/finish", h.RegisterFinish)
+	mux.HandleFunc("/v2/users/self/webauthn/login/begin", h.LoginBegin)
+	mux.HandleFunc("/v2/users/self/webauthn/login/finish", h.LoginFinish)
+}
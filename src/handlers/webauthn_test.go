@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/ihexxa/quickshare/src/db/rdb/rdbsql"
+)
+
+func authedRegisterBeginRequest(name string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v2/users/self/webauthn/register/begin", strings.NewReader(`{}`))
+	return req.WithContext(WithWebauthnUser(req.Context(), name))
+}
+
+func newTestHandlers(t *testing.T) *WebauthnHandlers {
+	t.Helper()
+
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          "localhost",
+		RPDisplayName: "quickshare",
+		RPOrigins:     []string{"http://localhost"},
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New: %v", err)
+	}
+
+	rawSt, err := (&rdbsql.SQLite{File: ":memory:"}).Open()
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	return NewWebauthnHandlers(wa, rawSt.(*rdbsql.Store))
+}
+
+func TestCredentialConversionRoundTrips(t *testing.T) {
+	orig := &rdbsql.Credential{
+		CredentialID:    []byte("cred-1"),
+		UserID:          7,
+		PublicKey:       []byte("pubkey"),
+		AttestationType: "none",
+		AAGUID:          []byte("aaguid"),
+		SignCount:       3,
+		CloneWarning:    false,
+		Transports:      []string{"usb", "nfc"},
+	}
+
+	lib := toLibCredential(orig)
+	back := fromLibCredential(orig.UserID, &lib)
+
+	if string(back.CredentialID) != string(orig.CredentialID) ||
+		back.UserID != orig.UserID ||
+		string(back.PublicKey) != string(orig.PublicKey) ||
+		back.AttestationType != orig.AttestationType ||
+		string(back.AAGUID) != string(orig.AAGUID) ||
+		back.SignCount != orig.SignCount ||
+		len(back.Transports) != len(orig.Transports) {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", back, orig)
+	}
+}
+
+func TestRegisterBeginUnknownUser(t *testing.T) {
+	h := newTestHandlers(t)
+
+	req := authedRegisterBeginRequest("nobody")
+	w := httptest.NewRecorder()
+	h.RegisterBegin(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown user, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterBeginRequiresAuthenticatedUser(t *testing.T) {
+	h := newTestHandlers(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/users/self/webauthn/register/begin", strings.NewReader(`{"name":"someone-elses-account"}`))
+	w := httptest.NewRecorder()
+	h.RegisterBegin(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without an authenticated session, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterFinishRequiresAuthenticatedUser(t *testing.T) {
+	h := newTestHandlers(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/users/self/webauthn/register/finish?name=someone-elses-account", nil)
+	w := httptest.NewRecorder()
+	h.RegisterFinish(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without an authenticated session, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLoginFinishUnknownUser(t *testing.T) {
+	h := newTestHandlers(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/users/self/webauthn/login/finish?name=nobody", nil)
+	w := httptest.NewRecorder()
+	h.LoginFinish(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown user, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisterRoutesMountsAllFourEndpoints(t *testing.T) {
+	h := newTestHandlers(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, path := range []string{
+		"/v2/users/self/webauthn/register/begin",
+		"/v2/users/self/webauthn/register/finish",
+		"/v2/users/self/webauthn/login/begin",
+		"/v2/users/self/webauthn/login/finish",
+	} {
+		if _, pattern := mux.Handler(httptest.NewRequest(http.MethodPost, path, nil)); pattern != path {
+			t.Fatalf("expected %q to be mounted, matched pattern %q", path, pattern)
+		}
+	}
+}